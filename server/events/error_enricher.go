@@ -0,0 +1,190 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnrichedError is a Terraform/HCL error with enough context to show the
+// offending line inline in a comment, rather than just the raw stderr.
+type EnrichedError struct {
+	File    string
+	Line    int
+	Snippet string
+	Message string
+}
+
+// diagnosticLocationRegex matches the classic HCL diagnostic header,
+// e.g. "on main.tf line 42:" or "on modules/vpc/main.tf line 7, in
+// resource...:".
+var diagnosticLocationRegex = regexp.MustCompile(`on (\S+) line (\d+)`)
+
+// jsonDiagnostic is the shape of one line of `terraform plan -json`
+// output that carries a diagnostic with a source location.
+type jsonDiagnostic struct {
+	Diagnostic struct {
+		Summary string `json:"summary"`
+		Detail  string `json:"detail"`
+		Range   struct {
+			Filename string `json:"filename"`
+			Start    struct {
+				Line int `json:"line"`
+			} `json:"start"`
+		} `json:"range"`
+	} `json:"diagnostic"`
+}
+
+// ErrorEnricher turns raw `terraform plan`/`init` stderr into an
+// EnrichedError when it can find a file and line number to point at,
+// following the pattern of surfacing errors with file context rather
+// than just dumping raw output.
+type ErrorEnricher struct {
+	// ContextLines is how many lines of surrounding source to include on
+	// either side of the error line. Defaults to 3 if zero.
+	ContextLines int
+}
+
+// NewErrorEnricher returns an ErrorEnricher with the default amount of
+// surrounding context.
+func NewErrorEnricher() *ErrorEnricher {
+	return &ErrorEnricher{ContextLines: 3}
+}
+
+// Enrich tries to extract a file/line location from rawErr, understanding
+// both the classic HCL diagnostic text and the newer `-json` diagnostic
+// format. workspaceDir is where rawErr's file paths are resolved
+// relative to. It returns ok=false if no location could be extracted or
+// the file couldn't be read, so callers should fall back to rendering
+// rawErr plainly.
+func (e *ErrorEnricher) Enrich(rawErr string, workspaceDir string) (*EnrichedError, bool) {
+	file, line, message, ok := locateError(rawErr)
+	if !ok {
+		return nil, false
+	}
+
+	path, ok := resolveWorkspacePath(workspaceDir, file)
+	if !ok {
+		return nil, false
+	}
+
+	snippet, ok := e.readSnippet(path, line)
+	if !ok {
+		return nil, false
+	}
+
+	return &EnrichedError{
+		File:    file,
+		Line:    line,
+		Snippet: snippet,
+		Message: message,
+	}, true
+}
+
+// resolveWorkspacePath joins workspaceDir with file (a path taken from
+// terraform's own error text, so it can't be trusted) and verifies the
+// result is still inside workspaceDir. This guards against a crafted
+// module source or diagnostic filename (e.g. "../../../etc/passwd")
+// being used to read and leak arbitrary files from the Atlantis host
+// back into a VCS comment.
+func resolveWorkspacePath(workspaceDir, file string) (string, bool) {
+	base, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return "", false
+	}
+	path, err := filepath.Abs(filepath.Join(base, file))
+	if err != nil {
+		return "", false
+	}
+	if path != base && !strings.HasPrefix(path, base+string(filepath.Separator)) {
+		return "", false
+	}
+	return path, true
+}
+
+// locateError finds a file+line in rawErr, trying the `-json` diagnostic
+// format first and falling back to the plain-text "on FILE line N:"
+// pattern that `terraform plan`/`init` print without `-json`.
+func locateError(rawErr string) (file string, line int, message string, ok bool) {
+	for _, l := range strings.Split(rawErr, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || l[0] != '{' {
+			continue
+		}
+		var diag jsonDiagnostic
+		if err := json.Unmarshal([]byte(l), &diag); err != nil {
+			continue
+		}
+		if diag.Diagnostic.Range.Filename != "" {
+			msg := diag.Diagnostic.Summary
+			if diag.Diagnostic.Detail != "" {
+				msg = msg + ": " + diag.Diagnostic.Detail
+			}
+			return diag.Diagnostic.Range.Filename, diag.Diagnostic.Range.Start.Line, msg, true
+		}
+	}
+
+	m := diagnosticLocationRegex.FindStringSubmatch(rawErr)
+	if m == nil {
+		return "", 0, "", false
+	}
+	line, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, "", false
+	}
+	return m[1], line, strings.TrimSpace(strings.SplitN(rawErr, "\n", 2)[0]), true
+}
+
+// readSnippet reads path and returns a fenced-ready snippet of the lines
+// around line, with a "> " marker on the offending line.
+func (e *ErrorEnricher) readSnippet(path string, line int) (string, bool) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(contents), "\n")
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+
+	contextLines := e.ContextLines
+	if contextLines == 0 {
+		contextLines = 3
+	}
+	start := line - contextLines
+	if start < 1 {
+		start = 1
+	}
+	end := line + contextLines
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		marker := "  "
+		if i == line {
+			marker = "> "
+		}
+		b.WriteString(marker)
+		b.WriteString(lines[i-1])
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), true
+}
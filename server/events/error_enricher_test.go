@@ -0,0 +1,65 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorEnricher_Enrich_PlainTextDiagnostic(t *testing.T) {
+	workspace := t.TempDir()
+	writeFile(t, workspace, "main.tf", "resource \"aws_instance\" \"web\" {\n  ami = \"bad\"\n}\n")
+
+	rawErr := "Error: Unsupported argument\n\n  on main.tf line 2, in resource \"aws_instance\" \"web\":\n  2:   ami = \"bad\"\n"
+	enriched, ok := NewErrorEnricher().Enrich(rawErr, workspace)
+	if !ok {
+		t.Fatal("expected Enrich to succeed")
+	}
+	if enriched.File != "main.tf" || enriched.Line != 2 {
+		t.Errorf("got File=%q Line=%d, want main.tf/2", enriched.File, enriched.Line)
+	}
+	if !strings.Contains(enriched.Snippet, "> ") {
+		t.Errorf("expected snippet to mark the offending line, got %q", enriched.Snippet)
+	}
+}
+
+func TestErrorEnricher_Enrich_NoLocationFallsBack(t *testing.T) {
+	_, ok := NewErrorEnricher().Enrich("Error: something went wrong", t.TempDir())
+	if ok {
+		t.Error("expected Enrich to fail when no file/line can be found")
+	}
+}
+
+func TestErrorEnricher_Enrich_RefusesPathOutsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	secretDir := t.TempDir()
+	secretPath := filepath.Join(secretDir, "secret.txt")
+	if err := os.WriteFile(secretPath, []byte("super secret contents\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rel, err := filepath.Rel(workspace, secretPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawErr := "Error: bad module\n\n  on " + rel + " line 1:\n"
+
+	enriched, ok := NewErrorEnricher().Enrich(rawErr, workspace)
+	if ok {
+		t.Fatalf("expected Enrich to refuse a path escaping the workspace, got %+v", enriched)
+	}
+}
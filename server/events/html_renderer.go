@@ -0,0 +1,62 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// HTMLRenderer renders a RenderModel as a safe HTML fragment; no HTTP
+// endpoint in this package serves it. It uses html/template rather than
+// text/template so project paths, error messages and Terraform output
+// can never break out of the page as raw markup.
+type HTMLRenderer struct{}
+
+// Render renders model as an HTML fragment. It implements Renderer.
+func (h *HTMLRenderer) Render(model RenderModel) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := htmlResultTmpl.Execute(buf, model); err != nil {
+		return "", fmt.Errorf("rendering html results: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var htmlResultTmpl = template.Must(template.New("htmlResult").Parse(`
+<div class="atlantis-result">
+  <h2>{{.Command}}</h2>
+  {{if .Error}}
+    <pre class="atlantis-error">{{.Error}}</pre>
+  {{else if .Failure}}
+    <p class="atlantis-failure">{{.Failure}}</p>
+  {{else}}
+    {{range .Projects}}
+      <div class="atlantis-project">
+        <h3>{{.Path}}</h3>
+        {{if .Error}}
+          <pre class="atlantis-error">{{.Error}}</pre>
+        {{else if .Failure}}
+          <p class="atlantis-failure">{{.Failure}}</p>
+        {{else if .PlanSuccess}}
+          <pre class="atlantis-diff">{{.PlanSuccess.TerraformOutput}}</pre>
+          {{if .PlanSuccess.LockURL}}<a href="{{.PlanSuccess.LockURL}}">discard this plan</a>{{end}}
+        {{else if .ApplySuccess}}
+          <pre class="atlantis-diff">{{.ApplySuccess}}</pre>
+        {{end}}
+      </div>
+    {{end}}
+  {{end}}
+</div>
+`))
@@ -0,0 +1,63 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import "regexp"
+
+// ImageReport is the vulnerability summary for a single container image,
+// as returned by a Clair-compatible scanning endpoint.
+type ImageReport struct {
+	Image    string
+	Critical int
+	High     int
+}
+
+// ImageScanner looks up known vulnerabilities for a set of container
+// image references. The only implementation in this package is
+// noopImageScanner, which MarkdownRenderer uses when its ImageScanner
+// field is nil; no implementation here queries a scanning endpoint.
+type ImageScanner interface {
+	Scan(refs []string) ([]ImageReport, error)
+}
+
+// noopImageScanner is the default ImageScanner: it reports nothing, so
+// plans that don't opt into scanning render exactly as before.
+type noopImageScanner struct{}
+
+// Scan implements ImageScanner.
+func (noopImageScanner) Scan(refs []string) ([]ImageReport, error) {
+	return nil, nil
+}
+
+// imageRefRegex matches `image = "..."` attributes as found in resources
+// like aws_ecs_task_definition container definitions, kubernetes_deployment
+// pod specs and google_cloud_run_service templates.
+var imageRefRegex = regexp.MustCompile(`image\s*=\s*"([^"]+)"`)
+
+// extractImageRefs scans terraform plan output for container image
+// references and returns the unique set found, in the order they first
+// appear.
+func extractImageRefs(output string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, m := range imageRefRegex.FindAllStringSubmatch(output, -1) {
+		ref := m[1]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractImageRefs(t *testing.T) {
+	output := `
+resource "aws_ecs_task_definition" "app" {
+  container_definitions = jsonencode([{
+    image = "repo/app:1.2.3"
+  }])
+}
+resource "kubernetes_deployment" "worker" {
+  spec {
+    template {
+      spec {
+        container {
+          image = "repo/worker:latest"
+        }
+      }
+    }
+  }
+}
+`
+	got := extractImageRefs(output)
+	want := []string{"repo/app:1.2.3", "repo/worker:latest"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractImageRefs_DedupesRepeatedReferences(t *testing.T) {
+	output := `image = "repo/app:1.2.3"` + "\n" + `image = "repo/app:1.2.3"`
+	got := extractImageRefs(output)
+	want := []string{"repo/app:1.2.3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractImageRefs_NoImages(t *testing.T) {
+	if refs := extractImageRefs("resource \"aws_instance\" \"web\" {}\n"); len(refs) != 0 {
+		t.Errorf("expected no image refs, got %v", refs)
+	}
+}
+
+func TestNoopImageScanner_ReturnsNothing(t *testing.T) {
+	reports, err := (noopImageScanner{}).Scan([]string{"repo/app:1.2.3"})
+	if err != nil || reports != nil {
+		t.Errorf("expected noopImageScanner to report nothing, got %v, %v", reports, err)
+	}
+}
+
+type fakeImageScanner struct {
+	reports []ImageReport
+}
+
+func (f fakeImageScanner) Scan(refs []string) ([]ImageReport, error) {
+	return f.reports, nil
+}
+
+func TestMarkdownRenderer_RenderImageVulnSection(t *testing.T) {
+	m := &MarkdownRenderer{
+		Templates:    NewDefaultTemplateSet(),
+		ImageScanner: fakeImageScanner{reports: []ImageReport{{Image: "repo/app:1.2.3", Critical: 2, High: 1}}},
+	}
+
+	out := m.renderImageVulnSection(m.Templates, `image = "repo/app:1.2.3"`)
+	if !strings.Contains(out, "Image Vulnerabilities") || !strings.Contains(out, "repo/app:1.2.3") {
+		t.Errorf("expected an image vulnerabilities section, got %q", out)
+	}
+}
+
+func TestMarkdownRenderer_RenderImageVulnSection_NoImagesNoSection(t *testing.T) {
+	m := &MarkdownRenderer{
+		Templates:    NewDefaultTemplateSet(),
+		ImageScanner: fakeImageScanner{reports: []ImageReport{{Image: "repo/app:1.2.3"}}},
+	}
+
+	out := m.renderImageVulnSection(m.Templates, `resource "aws_instance" "web" {}`)
+	if out != "" {
+		t.Errorf("expected no section when no images are referenced, got %q", out)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import "encoding/json"
+
+// JSONRenderer renders a RenderModel as stable JSON so external tooling
+// (CI systems, a results dashboard) can consume plan/apply outcomes
+// without scraping markdown. It implements Renderer.
+type JSONRenderer struct{}
+
+// jsonProject is the JSON shape of a single project's result. Only one of
+// Error, Failure, Plan and Apply is ever set.
+type jsonProject struct {
+	Path    string `json:"path"`
+	Error   string `json:"error,omitempty"`
+	Failure string `json:"failure,omitempty"`
+	Plan    *struct {
+		TerraformOutput string `json:"terraformOutput"`
+		LockURL         string `json:"lockURL,omitempty"`
+	} `json:"plan,omitempty"`
+	Apply string `json:"apply,omitempty"`
+}
+
+// jsonResponse is the top-level JSON shape returned by JSONRenderer.
+type jsonResponse struct {
+	Command  string        `json:"command"`
+	Error    string        `json:"error,omitempty"`
+	Failure  string        `json:"failure,omitempty"`
+	Projects []jsonProject `json:"projects,omitempty"`
+}
+
+// Render renders model as JSON. It implements Renderer.
+func (j *JSONRenderer) Render(model RenderModel) (string, error) {
+	resp := jsonResponse{
+		Command: model.Command,
+		Error:   model.Error,
+		Failure: model.Failure,
+	}
+	for _, p := range model.Projects {
+		jp := jsonProject{
+			Path:    p.Path,
+			Error:   p.Error,
+			Failure: p.Failure,
+			Apply:   p.ApplySuccess,
+		}
+		if p.PlanSuccess != nil {
+			jp.Plan = &struct {
+				TerraformOutput string `json:"terraformOutput"`
+				LockURL         string `json:"lockURL,omitempty"`
+			}{
+				TerraformOutput: p.PlanSuccess.TerraformOutput,
+				LockURL:         p.PlanSuccess.LockURL,
+			}
+		}
+		resp.Projects = append(resp.Projects, jp)
+	}
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -16,13 +16,52 @@ package events
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 )
 
-// MarkdownRenderer renders responses as markdown.
+// templatesDirName is the name of the directory, relative to a repo's root,
+// that a repo can use to override Atlantis' built-in comment templates.
+const templatesDirName = "atlantis-templates"
+
+// MarkdownRenderer renders a RenderModel as markdown, suitable for posting
+// as a VCS comment. It implements Renderer.
 type MarkdownRenderer struct {
-	LockURLBuilder func(string) string
+	// Templates holds the built-in templates used when a repo doesn't
+	// provide its own overrides.
+	Templates *TemplateSet
+	// PlanParser extracts a PlanSummary from each PlanSuccess's raw
+	// TerraformOutput so the template can show a resource table above
+	// the diff. Defaults to NewPlanParser() if nil.
+	PlanParser *PlanParser
+	// ErrorEnricher finds a file/line location in a top-level or
+	// per-project error and attaches a source snippet so the comment can
+	// point at the offending line. Defaults to NewErrorEnricher() if nil.
+	ErrorEnricher *ErrorEnricher
+	// ImageScanner looks up known vulnerabilities for container images
+	// referenced in a plan, so operators can spot risky base images
+	// before merging. Defaults to a no-op scanner if nil.
+	ImageScanner ImageScanner
+}
+
+// NewMarkdownRenderer returns a MarkdownRenderer using the built-in
+// templates.
+func NewMarkdownRenderer() *MarkdownRenderer {
+	return &MarkdownRenderer{
+		Templates:     NewDefaultTemplateSet(),
+		PlanParser:    NewPlanParser(),
+		ErrorEnricher: NewErrorEnricher(),
+		ImageScanner:  noopImageScanner{},
+	}
+}
+
+// planTemplateData is what's handed to the planSuccess template: the raw
+// PlanSuccess plus the structured summary PlanParser extracted from it.
+type planTemplateData struct {
+	*PlanSuccess
+	Summary PlanSummary
 }
 
 // CommonData is data that all responses have.
@@ -30,6 +69,12 @@ type CommonData struct {
 	Command string
 	Verbose bool
 	Log     string
+	// LogSection is templates.Log already rendered against this same
+	// data, so other templates just interpolate it with {{.LogSection}}
+	// instead of each baking in their own copy of the verbose-log markup.
+	// This is what makes an atlantis-templates/log.tmpl override actually
+	// take effect.
+	LogSection string
 }
 
 // ErrData is data about an error response.
@@ -38,6 +83,13 @@ type ErrData struct {
 	CommonData
 }
 
+// ErrWithContextData is data about an error response that was enriched
+// with a source snippet.
+type ErrWithContextData struct {
+	EnrichedError
+	CommonData
+}
+
 // FailureData is data about a failure response.
 type FailureData struct {
 	Failure string
@@ -50,58 +102,220 @@ type ResultData struct {
 	CommonData
 }
 
-// Render formats the data into a markdown string.
-// nolint: interfacer
-func (m *MarkdownRenderer) Render(res CommandResponse, cmdName CommandName, log string, verbose bool) string {
-	commandStr := strings.Title(cmdName.String())
-	common := CommonData{commandStr, verbose, log}
-	if res.Error != nil {
-		return m.renderTemplate(errWithLogTmpl, ErrData{res.Error.Error(), common})
+// TemplateSet holds all of the templates used to render comments. Each
+// field is named after the template it holds so that LoadTemplates can
+// key overrides off of the same names.
+type TemplateSet struct {
+	SingleProject  *template.Template
+	MultiProject   *template.Template
+	PlanSuccess    *template.Template
+	ApplySuccess   *template.Template
+	Err            *template.Template
+	ErrWithContext *template.Template
+	Failure        *template.Template
+	Log            *template.Template
+	ImageVuln      *template.Template
+}
+
+// templateFuncMap contains helpers available to both the built-in and
+// user-provided templates.
+var templateFuncMap = template.FuncMap{
+	"trim": strings.TrimSpace,
+	"truncate": func(s string, max int) string {
+		if max <= 0 || len(s) <= max {
+			return s
+		}
+		return s[:max] + "\n...[truncated]"
+	},
+	"codeFence": func(lang, s string) string {
+		return fmt.Sprintf("```%s\n%s\n```", lang, s)
+	},
+}
+
+// NewDefaultTemplateSet returns the built-in templates Atlantis ships with.
+func NewDefaultTemplateSet() *TemplateSet {
+	return &TemplateSet{
+		SingleProject:  parseTmpl("singleProject", singleProjectTmplText+logSectionTmplText),
+		MultiProject:   parseTmpl("multiProject", multiProjectTmplText+logSectionTmplText),
+		PlanSuccess:    parseTmpl("planSuccess", planSuccessTmplText),
+		ApplySuccess:   parseTmpl("applySuccess", applySuccessTmplText),
+		Err:            parseTmpl("err", errTmplText+logSectionTmplText),
+		ErrWithContext: parseTmpl("errWithContext", errWithContextTmplText+logSectionTmplText),
+		Failure:        parseTmpl("failure", failureTmplText+logSectionTmplText),
+		Log:            parseTmpl("log", logTmplText),
+		ImageVuln:      parseTmpl("imageVuln", imageVulnTmplText),
 	}
-	if res.Failure != "" {
-		return m.renderTemplate(failureWithLogTmpl, FailureData{res.Failure, common})
+}
+
+// LoadTemplates walks dir looking for <name>.tmpl files matching the
+// TemplateSet field names (singleProject.tmpl, multiProject.tmpl,
+// planSuccess.tmpl, applySuccess.tmpl, err.tmpl, errWithContext.tmpl,
+// failure.tmpl, log.tmpl, imageVuln.tmpl) and returns a TemplateSet with
+// those overrides applied. Any name not found in dir falls back to the
+// built-in template so a repo only needs to ship the templates it wants
+// to customize.
+func LoadTemplates(dir string) (*TemplateSet, error) {
+	set := NewDefaultTemplateSet()
+
+	overrides := map[string]**template.Template{
+		"singleProject":  &set.SingleProject,
+		"multiProject":   &set.MultiProject,
+		"planSuccess":    &set.PlanSuccess,
+		"applySuccess":   &set.ApplySuccess,
+		"err":            &set.Err,
+		"errWithContext": &set.ErrWithContext,
+		"failure":        &set.Failure,
+		"log":            &set.Log,
+		"imageVuln":      &set.ImageVuln,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return set, nil
+		}
+		return nil, fmt.Errorf("reading templates dir %q: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		field, ok := overrides[name]
+		if !ok {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading template %q: %w", entry.Name(), err)
+		}
+		tmpl, err := parseTmplErr(name, string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %q: %w", entry.Name(), err)
+		}
+		*field = tmpl
 	}
-	return m.renderProjectResults(res.ProjectResults, common)
+	return set, nil
 }
 
-func (m *MarkdownRenderer) renderProjectResults(pathResults []ProjectResult, common CommonData) string {
+// templatesForRepo returns the TemplateSet to use when rendering a comment
+// for the repo checked out at repoDir. If repoDir contains an
+// atlantis-templates/ directory, the result of LoadTemplates on it (the
+// built-in templates with the repo's overrides applied) is used in place
+// of m.Templates; otherwise m.Templates (the built-ins, unless set
+// otherwise) is used.
+func (m *MarkdownRenderer) templatesForRepo(repoDir string) *TemplateSet {
+	base := m.Templates
+	if base == nil {
+		base = NewDefaultTemplateSet()
+	}
+	if repoDir == "" {
+		return base
+	}
+	overrideDir := filepath.Join(repoDir, templatesDirName)
+	if _, err := os.Stat(overrideDir); err != nil {
+		return base
+	}
+	set, err := LoadTemplates(overrideDir)
+	if err != nil {
+		// Fall back to the base templates rather than failing the whole
+		// comment if a repo ships a broken override.
+		return base
+	}
+	return set
+}
+
+// Render renders model as markdown. It implements Renderer.
+func (m *MarkdownRenderer) Render(model RenderModel) (string, error) {
+	templates := m.templatesForRepo(model.RepoDir)
+	common := CommonData{Command: model.Command, Verbose: model.Verbose, Log: model.Log}
+	common.LogSection = m.renderTemplate(templates.Log, common)
+	if model.Error != "" {
+		if enriched, ok := m.enricher().Enrich(model.Error, model.RepoDir); ok {
+			return m.renderTemplate(templates.ErrWithContext, ErrWithContextData{*enriched, common}), nil
+		}
+		return m.renderTemplate(templates.Err, ErrData{model.Error, common}), nil
+	}
+	if model.Failure != "" {
+		return m.renderTemplate(templates.Failure, FailureData{model.Failure, common}), nil
+	}
+	return m.renderProjectResults(model.Projects, model.RepoDir, common, templates), nil
+}
+
+// enricher returns m.ErrorEnricher, falling back to the default if unset.
+func (m *MarkdownRenderer) enricher() *ErrorEnricher {
+	if m.ErrorEnricher != nil {
+		return m.ErrorEnricher
+	}
+	return NewErrorEnricher()
+}
+
+func (m *MarkdownRenderer) renderProjectResults(projects []ProjectRenderModel, repoDir string, common CommonData, templates *TemplateSet) string {
 	results := make(map[string]string)
-	for _, result := range pathResults {
-		if result.Error != nil {
-			results[result.Path] = m.renderTemplate(errTmpl, struct {
-				Command string
-				Error   string
-			}{
-				Command: common.Command,
-				Error:   result.Error.Error(),
-			})
-		} else if result.Failure != "" {
-			results[result.Path] = m.renderTemplate(failureTmpl, struct {
-				Command string
-				Failure string
-			}{
-				Command: common.Command,
-				Failure: result.Failure,
-			})
-		} else if result.PlanSuccess != nil {
-			result.PlanSuccess.LockURL = m.LockURLBuilder(result.PlanSuccess.LockKey)
-			results[result.Path] = m.renderTemplate(planSuccessTmpl, *result.PlanSuccess)
-		} else if result.ApplySuccess != "" {
-			results[result.Path] = m.renderTemplate(applySuccessTmpl, struct{ Output string }{result.ApplySuccess})
+	for _, p := range projects {
+		if p.Error != "" {
+			// terraform's file/line diagnostics in a per-project error are
+			// relative to that project's own directory, not the repo root,
+			// so that's the workspace we resolve the snippet against.
+			if enriched, ok := m.enricher().Enrich(p.Error, filepath.Join(repoDir, p.Path)); ok {
+				results[p.Path] = m.renderTemplate(templates.ErrWithContext, ErrWithContextData{*enriched, common})
+				continue
+			}
+			results[p.Path] = m.renderTemplate(templates.Err, ErrData{p.Error, common})
+		} else if p.Failure != "" {
+			results[p.Path] = m.renderTemplate(templates.Failure, FailureData{p.Failure, common})
+		} else if p.PlanSuccess != nil {
+			parser := m.PlanParser
+			if parser == nil {
+				parser = NewPlanParser()
+			}
+			data := planTemplateData{
+				PlanSuccess: p.PlanSuccess,
+				Summary:     parser.Parse(p.PlanSuccess.TerraformOutput),
+			}
+			output := m.renderTemplate(templates.PlanSuccess, data)
+			output += m.renderImageVulnSection(templates, p.PlanSuccess.TerraformOutput)
+			results[p.Path] = output
+		} else if p.ApplySuccess != "" {
+			results[p.Path] = m.renderTemplate(templates.ApplySuccess, struct{ Output string }{p.ApplySuccess})
 		} else {
-			results[result.Path] = "Found no template. This is a bug!"
+			results[p.Path] = "Found no template. This is a bug!"
 		}
 	}
 
 	var tmpl *template.Template
 	if len(results) == 1 {
-		tmpl = singleProjectTmpl
+		tmpl = templates.SingleProject
 	} else {
-		tmpl = multiProjectTmpl
+		tmpl = templates.MultiProject
 	}
 	return m.renderTemplate(tmpl, ResultData{results, common})
 }
 
+// renderImageVulnSection scans terraformOutput for container image
+// references and, if the configured ImageScanner has anything to report
+// on them, renders an "Image Vulnerabilities" section to append after
+// the plan. It renders nothing if no images are referenced or the
+// scanner has no findings.
+func (m *MarkdownRenderer) renderImageVulnSection(templates *TemplateSet, terraformOutput string) string {
+	refs := extractImageRefs(terraformOutput)
+	if len(refs) == 0 {
+		return ""
+	}
+
+	scanner := m.ImageScanner
+	if scanner == nil {
+		scanner = noopImageScanner{}
+	}
+	reports, err := scanner.Scan(refs)
+	if err != nil || len(reports) == 0 {
+		return ""
+	}
+
+	return m.renderTemplate(templates.ImageVuln, struct{ Reports []ImageReport }{reports})
+}
+
 func (m *MarkdownRenderer) renderTemplate(tmpl *template.Template, data interface{}) string {
 	buf := &bytes.Buffer{}
 	if err := tmpl.Execute(buf, data); err != nil {
@@ -110,33 +324,57 @@ func (m *MarkdownRenderer) renderTemplate(tmpl *template.Template, data interfac
 	return buf.String()
 }
 
-var singleProjectTmpl = template.Must(template.New("").Parse("{{ range $result := .Results }}{{$result}}{{end}}\n" + logTmpl))
-var multiProjectTmpl = template.Must(template.New("").Parse(
-	"Ran {{.Command}} in {{ len .Results }} directories:\n" +
-		"{{ range $path, $result := .Results }}" +
-		" * `{{$path}}`\n" +
-		"{{end}}\n" +
-		"{{ range $path, $result := .Results }}" +
-		"## {{$path}}/\n" +
-		"{{$result}}\n" +
-		"---\n{{end}}" +
-		logTmpl))
-var planSuccessTmpl = template.Must(template.New("").Parse(
-	"```diff\n" +
-		"{{.TerraformOutput}}\n" +
-		"```\n\n" +
-		"* To **discard** this plan click [here]({{.LockURL}})."))
-var applySuccessTmpl = template.Must(template.New("").Parse(
+func parseTmpl(name, text string) *template.Template {
+	return template.Must(parseTmplErr(name, text))
+}
+
+func parseTmplErr(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncMap).Parse(text)
+}
+
+const singleProjectTmplText = "{{ range $result := .Results }}{{$result}}{{end}}\n"
+const multiProjectTmplText = "Ran {{.Command}} in {{ len .Results }} directories:\n" +
+	"{{ range $path, $result := .Results }}" +
+	" * `{{$path}}`\n" +
+	"{{end}}\n" +
+	"{{ range $path, $result := .Results }}" +
+	"## {{$path}}/\n" +
+	"{{$result}}\n" +
+	"---\n{{end}}"
+const planSuccessTmplText = "{{ if .Summary.Resources }}" +
+	"Plan: {{.Summary.Adds}} to add, {{.Summary.Changes}} to change, {{.Summary.Destroys}} to destroy.\n\n" +
+	"{{ if .Summary.HasDestroys }}:warning: **This plan destroys one or more resources.**\n\n{{ end }}" +
+	"| resource | action | module |\n" +
+	"| --- | --- | --- |\n" +
+	"{{ range .Summary.Resources }}| `{{.Resource}}` | {{.Action}} | {{ if .Module }}`{{.Module}}`{{ else }}-{{ end }} |\n{{ end }}" +
+	"\n" +
+	"{{ end }}" +
 	"```diff\n" +
-		"{{.Output}}\n" +
-		"```"))
-var errTmplText = "**{{.Command}} Error**\n" +
+	"{{.TerraformOutput}}\n" +
+	"```\n\n" +
+	"* To **discard** this plan click [here]({{.LockURL}})."
+const applySuccessTmplText = "```diff\n" +
+	"{{.Output}}\n" +
+	"```"
+const errTmplText = "**{{.Command}} Error**\n" +
 	"```\n" +
 	"{{.Error}}\n" +
 	"```\n"
-var errTmpl = template.Must(template.New("").Parse(errTmplText))
-var errWithLogTmpl = template.Must(template.New("").Parse(errTmplText + logTmpl))
-var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}\n"
-var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
-var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
-var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
+const errWithContextTmplText = "**{{.Command}} Error**\n" +
+	"`{{.File}}` line {{.Line}}\n" +
+	"```\n" +
+	"{{.Snippet}}\n" +
+	"```\n" +
+	"{{.Message}}\n"
+const failureTmplText = "**{{.Command}} Failed**: {{.Failure}}\n"
+const imageVulnTmplText = "\n**Image Vulnerabilities**\n\n" +
+	"| image | critical | high |\n" +
+	"| --- | --- | --- |\n" +
+	"{{ range .Reports }}| `{{.Image}}` | {{.Critical}} | {{.High}} |\n{{ end }}"
+const logTmplText = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
+
+// logSectionTmplText is appended to every template that shows a verbose
+// log so they all render templates.Log's output (rather than baking in
+// their own copy of logTmplText), which is what lets an
+// atlantis-templates/log.tmpl override actually change what's posted.
+const logSectionTmplText = "{{.LogSection}}"
@@ -0,0 +1,177 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestLoadTemplates_MissingDirFallsBackToDefaults(t *testing.T) {
+	set, err := LoadTemplates(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err != nil {
+		t.Fatalf("expected no error for a missing dir, got %v", err)
+	}
+	if set.Err == nil || set.PlanSuccess == nil {
+		t.Fatal("expected a full set of built-in templates when the dir is missing")
+	}
+}
+
+func TestLoadTemplates_OverridesOnlyNamedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "err.tmpl", "CUSTOM ERR: {{.Error}}")
+	writeFile(t, dir, "not-a-template.txt", "ignored")
+
+	set, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	got := renderToString(t, set.Err, ErrData{Error: "boom", CommonData: CommonData{Command: "Plan"}})
+	if !strings.Contains(got, "CUSTOM ERR: boom") {
+		t.Errorf("expected overridden err template to be used, got %q", got)
+	}
+
+	// failure.tmpl wasn't overridden, so it should still be the built-in.
+	gotFailure := renderToString(t, set.Failure, FailureData{Failure: "nope", CommonData: CommonData{Command: "Plan"}})
+	if !strings.Contains(gotFailure, "**Plan Failed**: nope") {
+		t.Errorf("expected the built-in failure template to still be used, got %q", gotFailure)
+	}
+}
+
+func TestMarkdownRenderer_Render_ProjectErrorHonorsTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "err.tmpl", "CUSTOM ERR: {{.Error}}")
+	set, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	m := &MarkdownRenderer{Templates: set}
+	model := RenderModel{
+		Command: "Plan",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", Error: "boom"},
+		},
+	}
+
+	out, err := m.Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "CUSTOM ERR: boom") {
+		t.Errorf("expected per-project error to honor the atlantis-templates override, got %q", out)
+	}
+}
+
+func TestMarkdownRenderer_Render_ProjectFailureHonorsTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "failure.tmpl", "CUSTOM FAILURE: {{.Failure}}")
+	set, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	m := &MarkdownRenderer{Templates: set}
+	model := RenderModel{
+		Command: "Plan",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", Failure: "nope"},
+		},
+	}
+
+	out, err := m.Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "CUSTOM FAILURE: nope") {
+		t.Errorf("expected per-project failure to honor the atlantis-templates override, got %q", out)
+	}
+}
+
+func TestMarkdownRenderer_Render_LogOverrideTakesEffect(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "log.tmpl", "CUSTOM LOG: {{.Log}}")
+	set, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	m := &MarkdownRenderer{Templates: set}
+	model := RenderModel{
+		Command: "Plan",
+		Verbose: true,
+		Log:     "terraform init output",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", ApplySuccess: "applied ok"},
+		},
+	}
+
+	out, err := m.Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "CUSTOM LOG: terraform init output") {
+		t.Errorf("expected the overridden log.tmpl to be rendered into the comment, got %q", out)
+	}
+}
+
+func TestMarkdownRenderer_Render_ProjectErrorEnrichedWithProjectRelativeWorkspace(t *testing.T) {
+	repoDir := t.TempDir()
+	projectDir := filepath.Join(repoDir, "proj1")
+	if err := os.MkdirAll(projectDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, projectDir, "main.tf", "resource \"aws_instance\" \"web\" {\n  ami = \"bad\"\n}\n")
+
+	m := &MarkdownRenderer{Templates: NewDefaultTemplateSet()}
+	model := RenderModel{
+		Command: "Plan",
+		RepoDir: repoDir,
+		Projects: []ProjectRenderModel{
+			{
+				Path:  "proj1",
+				Error: "Error: Unsupported argument\n\n  on main.tf line 2, in resource \"aws_instance\" \"web\":\n  2:   ami = \"bad\"\n",
+			},
+		},
+	}
+
+	out, err := m.Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "main.tf") || !strings.Contains(out, "> ") {
+		t.Errorf("expected the per-project error to be enriched with a snippet from proj1/main.tf, got %q", out)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+func renderToString(t *testing.T, tmpl *template.Template, data interface{}) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		t.Fatalf("executing template: %v", err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlanSummary is a structured summary of a terraform plan's output,
+// extracted by PlanParser so comment templates (and downstream policy
+// code that wants to gate applies on destroy counts) don't have to
+// re-parse raw plan text themselves.
+type PlanSummary struct {
+	Adds     int
+	Changes  int
+	Destroys int
+
+	Resources []ResourceChange
+}
+
+// HasDestroys returns true if the plan destroys at least one resource.
+func (s PlanSummary) HasDestroys() bool {
+	return s.Destroys > 0
+}
+
+// ResourceChange is a single resource affected by a plan.
+type ResourceChange struct {
+	// Module is the resource's module path, e.g. "module.vpc", or "" if
+	// it's in the root module.
+	Module string
+	// Resource is the resource's address within its module, e.g.
+	// "aws_instance.web".
+	Resource string
+	// Action is one of "create", "update", "destroy" or "replace".
+	Action string
+}
+
+var planCountsRegex = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+var resourceHeaderRegex = regexp.MustCompile(`(?m)^\s*#\s+(\S+)\s+(?:will be|must be) (created|updated in-place|destroyed|replaced)`)
+var moduleAddrRegex = regexp.MustCompile(`^((?:module\.[^.]+\.)*)([^.]+\.[^.]+)$`)
+
+// PlanParser parses the raw output of `terraform plan` into a
+// PlanSummary.
+type PlanParser struct{}
+
+// NewPlanParser returns a PlanParser.
+func NewPlanParser() *PlanParser {
+	return &PlanParser{}
+}
+
+// Parse extracts a PlanSummary from output, the raw stdout of
+// `terraform plan`. If output doesn't contain a recognizable
+// "Plan: X to add, Y to change, Z to destroy" line, it returns a
+// zero-value PlanSummary so callers can fall back to rendering the raw
+// diff only.
+func (p *PlanParser) Parse(output string) PlanSummary {
+	var summary PlanSummary
+
+	m := planCountsRegex.FindStringSubmatch(output)
+	if m == nil {
+		return summary
+	}
+	summary.Adds, _ = strconv.Atoi(m[1])
+	summary.Changes, _ = strconv.Atoi(m[2])
+	summary.Destroys, _ = strconv.Atoi(m[3])
+
+	for _, m := range resourceHeaderRegex.FindAllStringSubmatch(output, -1) {
+		module, resource := splitModuleAddr(m[1])
+		summary.Resources = append(summary.Resources, ResourceChange{
+			Module:   module,
+			Resource: resource,
+			Action:   planAction(m[2]),
+		})
+	}
+
+	return summary
+}
+
+func planAction(verb string) string {
+	switch verb {
+	case "created":
+		return "create"
+	case "updated in-place":
+		return "update"
+	case "destroyed":
+		return "destroy"
+	case "replaced":
+		return "replace"
+	default:
+		return verb
+	}
+}
+
+// splitModuleAddr splits a resource address like
+// "module.foo.aws_instance.bar" into its module path ("module.foo") and
+// resource address ("aws_instance.bar"). Root-module resources have an
+// empty module.
+func splitModuleAddr(addr string) (module, resource string) {
+	m := moduleAddrRegex.FindStringSubmatch(addr)
+	if m == nil {
+		return "", addr
+	}
+	return strings.TrimSuffix(m[1], "."), m[2]
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import "testing"
+
+func TestPlanParser_Parse(t *testing.T) {
+	cases := []struct {
+		desc    string
+		output  string
+		summary PlanSummary
+	}{
+		{
+			desc:    "no plan summary line",
+			output:  "No changes. Infrastructure is up-to-date.",
+			summary: PlanSummary{},
+		},
+		{
+			desc: "create, update and destroy",
+			output: "  # aws_instance.web will be created\n" +
+				"  # aws_s3_bucket.logs will be updated in-place\n" +
+				"  # aws_instance.old will be destroyed\n" +
+				"Plan: 1 to add, 1 to change, 1 to destroy.",
+			summary: PlanSummary{
+				Adds: 1, Changes: 1, Destroys: 1,
+				Resources: []ResourceChange{
+					{Resource: "aws_instance.web", Action: "create"},
+					{Resource: "aws_s3_bucket.logs", Action: "update"},
+					{Resource: "aws_instance.old", Action: "destroy"},
+				},
+			},
+		},
+		{
+			desc: "forced replacement uses 'must be replaced'",
+			output: "  # aws_instance.web must be replaced\n" +
+				"Plan: 1 to add, 0 to change, 1 to destroy.",
+			summary: PlanSummary{
+				Adds: 1, Destroys: 1,
+				Resources: []ResourceChange{
+					{Resource: "aws_instance.web", Action: "replace"},
+				},
+			},
+		},
+		{
+			desc: "resource in a module",
+			output: "  # module.vpc.aws_subnet.public will be created\n" +
+				"Plan: 1 to add, 0 to change, 0 to destroy.",
+			summary: PlanSummary{
+				Adds: 1,
+				Resources: []ResourceChange{
+					{Module: "module.vpc", Resource: "aws_subnet.public", Action: "create"},
+				},
+			},
+		},
+	}
+
+	parser := NewPlanParser()
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got := parser.Parse(c.output)
+			if got.Adds != c.summary.Adds || got.Changes != c.summary.Changes || got.Destroys != c.summary.Destroys {
+				t.Errorf("counts: got %+v, want %+v", got, c.summary)
+			}
+			if len(got.Resources) != len(c.summary.Resources) {
+				t.Fatalf("resources: got %v, want %v", got.Resources, c.summary.Resources)
+			}
+			for i, r := range got.Resources {
+				if r != c.summary.Resources[i] {
+					t.Errorf("resource %d: got %+v, want %+v", i, r, c.summary.Resources[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlanSummary_HasDestroys(t *testing.T) {
+	if (PlanSummary{Destroys: 0}).HasDestroys() {
+		t.Error("expected HasDestroys to be false when Destroys is 0")
+	}
+	if !(PlanSummary{Destroys: 1}).HasDestroys() {
+		t.Error("expected HasDestroys to be true when Destroys > 0")
+	}
+}
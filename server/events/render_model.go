@@ -0,0 +1,87 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import "strings"
+
+// RenderModel is the renderer-agnostic view of a command response. It's
+// built once per comment via BuildRenderModel and then handed to whichever
+// Renderer is producing output for a given notification sink, so the
+// parsing of CommandResponse/ProjectResult only has to happen in one place.
+type RenderModel struct {
+	Command string
+	Verbose bool
+	Log     string
+
+	// Error and Failure are set when the command failed before any
+	// per-project results were produced. At most one of Error, Failure and
+	// Projects is populated.
+	Error    string
+	Failure  string
+	Projects []ProjectRenderModel
+
+	// RepoDir is the path to the checked-out repo, if known. Renderers
+	// that support per-repo overrides (currently just MarkdownRenderer)
+	// use it to look up those overrides.
+	RepoDir string
+}
+
+// ProjectRenderModel is the neutral view of a single ProjectResult.
+type ProjectRenderModel struct {
+	Path string
+
+	Error        string
+	Failure      string
+	PlanSuccess  *PlanSuccess
+	ApplySuccess string
+}
+
+// BuildRenderModel converts a CommandResponse into the neutral RenderModel
+// that every Renderer implementation consumes. lockURLBuilder is used to
+// fill in PlanSuccess.LockURL from its LockKey, mirroring what
+// MarkdownRenderer used to do inline.
+func BuildRenderModel(res CommandResponse, cmdName CommandName, log string, verbose bool, repoDir string, lockURLBuilder func(string) string) RenderModel {
+	model := RenderModel{
+		Command: strings.Title(cmdName.String()),
+		Verbose: verbose,
+		Log:     log,
+		RepoDir: repoDir,
+	}
+
+	if res.Error != nil {
+		model.Error = res.Error.Error()
+		return model
+	}
+	if res.Failure != "" {
+		model.Failure = res.Failure
+		return model
+	}
+
+	for _, result := range res.ProjectResults {
+		p := ProjectRenderModel{Path: result.Path}
+		switch {
+		case result.Error != nil:
+			p.Error = result.Error.Error()
+		case result.Failure != "":
+			p.Failure = result.Failure
+		case result.PlanSuccess != nil:
+			result.PlanSuccess.LockURL = lockURLBuilder(result.PlanSuccess.LockKey)
+			p.PlanSuccess = result.PlanSuccess
+		case result.ApplySuccess != "":
+			p.ApplySuccess = result.ApplySuccess
+		}
+		model.Projects = append(model.Projects, p)
+	}
+	return model
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+// Renderer turns a RenderModel into a format-specific comment body. Each
+// notification sink (VCS comment, Slack webhook, results dashboard, CI
+// tooling) picks the implementation that matches what it can display.
+type Renderer interface {
+	// Render renders model into this renderer's output format.
+	Render(model RenderModel) (string, error)
+}
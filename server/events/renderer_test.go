@@ -0,0 +1,119 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONRenderer_Render(t *testing.T) {
+	model := RenderModel{
+		Command: "Plan",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", PlanSuccess: &PlanSuccess{TerraformOutput: "diff", LockURL: "http://lock"}},
+			{Path: "proj2", Failure: "needs approval"},
+		},
+	}
+
+	out, err := (&JSONRenderer{}).Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{`"command":"Plan"`, `"path":"proj1"`, `"terraformOutput":"diff"`, `"path":"proj2"`, `"failure":"needs approval"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected JSON output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestJSONRenderer_Render_TopLevelError(t *testing.T) {
+	out, err := (&JSONRenderer{}).Render(RenderModel{Command: "Plan", Error: "boom"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, `"error":"boom"`) {
+		t.Errorf("expected top-level error in JSON output, got %q", out)
+	}
+}
+
+func TestHTMLRenderer_Render_EscapesUserContent(t *testing.T) {
+	model := RenderModel{
+		Command: "Plan",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", Error: "<script>alert(1)</script>"},
+		},
+	}
+
+	out, err := (&HTMLRenderer{}).Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(out, "<script>") {
+		t.Errorf("expected html/template to escape error content, got %q", out)
+	}
+	if !strings.Contains(out, "&lt;script&gt;") {
+		t.Errorf("expected escaped script tag in output, got %q", out)
+	}
+}
+
+func TestSlackRenderer_Render(t *testing.T) {
+	model := RenderModel{
+		Command: "Apply",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", ApplySuccess: "applied ok"},
+		},
+	}
+
+	out, err := (&SlackRenderer{}).Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{`"type":"header"`, "Apply", "proj1", "applied ok"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected Slack output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestSlackRenderer_Render_TruncatesOversizedSectionText(t *testing.T) {
+	model := RenderModel{
+		Command: "Plan",
+		Projects: []ProjectRenderModel{
+			{Path: "proj1", PlanSuccess: &PlanSuccess{TerraformOutput: strings.Repeat("x", 10000)}},
+		},
+	}
+
+	out, err := (&SlackRenderer{}).Render(model)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var msg slackMessage
+	if err := json.Unmarshal([]byte(out), &msg); err != nil {
+		t.Fatalf("unmarshaling Slack message: %v", err)
+	}
+	for _, b := range msg.Blocks {
+		if b.Text == nil {
+			continue
+		}
+		if len(b.Text.Text) > slackMaxSectionTextLen {
+			t.Errorf("expected section text to be truncated to %d chars, got %d", slackMaxSectionTextLen, len(b.Text.Text))
+		}
+	}
+	if !strings.Contains(out, "...[truncated]") {
+		t.Errorf("expected truncated output to note that it was truncated, got %q", out)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 HootSuite Media Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the License);
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Modified hereafter by contributors to runatlantis/atlantis.
+//
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SlackRenderer renders a RenderModel as a Slack Block Kit message so
+// Atlantis can post rich attachments (e.g. when a plan fails) to a Slack
+// webhook notifier. It implements Renderer.
+type SlackRenderer struct{}
+
+// slackBlock is a single Block Kit block. We only ever emit "header",
+// "section" and "divider" blocks, so Text is left nil for dividers.
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackMessage is the top-level payload a Slack incoming webhook expects.
+type slackMessage struct {
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// Render renders model as a JSON-encoded Slack Block Kit message. It
+// implements Renderer.
+func (s *SlackRenderer) Render(model RenderModel) (string, error) {
+	msg := slackMessage{
+		Blocks: []slackBlock{headerBlock(model.Command)},
+	}
+
+	switch {
+	case model.Error != "":
+		msg.Blocks = append(msg.Blocks, sectionBlock(fmt.Sprintf("*Error*\n```%s```", model.Error)))
+	case model.Failure != "":
+		msg.Blocks = append(msg.Blocks, sectionBlock(fmt.Sprintf("*Failed*: %s", model.Failure)))
+	default:
+		for _, p := range model.Projects {
+			msg.Blocks = append(msg.Blocks, dividerBlock(), sectionBlock(fmt.Sprintf("*%s*", p.Path)))
+			switch {
+			case p.Error != "":
+				msg.Blocks = append(msg.Blocks, sectionBlock(fmt.Sprintf("```%s```", p.Error)))
+			case p.Failure != "":
+				msg.Blocks = append(msg.Blocks, sectionBlock(p.Failure))
+			case p.PlanSuccess != nil:
+				msg.Blocks = append(msg.Blocks, sectionBlock(fmt.Sprintf("```%s```", p.PlanSuccess.TerraformOutput)))
+			case p.ApplySuccess != "":
+				msg.Blocks = append(msg.Blocks, sectionBlock(fmt.Sprintf("```%s```", p.ApplySuccess)))
+			}
+		}
+	}
+
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func headerBlock(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: text}}
+}
+
+// slackMaxSectionTextLen is Slack's limit on the length of a section
+// block's mrkdwn text. Posting a block over this size gets the whole
+// message rejected by the webhook, so anything longer is truncated.
+// https://api.slack.com/reference/block-kit/blocks#section_fields
+const slackMaxSectionTextLen = 3000
+
+func sectionBlock(markdown string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncateSlackText(markdown)}}
+}
+
+// truncateSlackText shortens s to fit within slackMaxSectionTextLen,
+// closing any code fence left open by the cut so the truncated block
+// still renders as valid mrkdwn.
+func truncateSlackText(s string) string {
+	if len(s) <= slackMaxSectionTextLen {
+		return s
+	}
+	const suffix = "\n...[truncated]"
+	const fence = "```"
+	cut := s[:slackMaxSectionTextLen-len(suffix)-len(fence)]
+	if strings.Count(cut, fence)%2 != 0 {
+		cut += fence
+	}
+	return cut + suffix
+}
+
+func dividerBlock() slackBlock {
+	return slackBlock{Type: "divider"}
+}